@@ -2,14 +2,12 @@ package avatar
 
 import (
 	"bufio"
-	"bytes"
 	"fmt"
 	"image"
 	"image/draw"
-	"image/png"
-	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -27,7 +25,6 @@ const (
 	imageHeight     = 500.0
 	dpi             = 72.0
 	spacer          = 20
-	textY           = 320
 )
 
 type Avatar struct {
@@ -42,6 +39,18 @@ type Avatar struct {
 
 	FontColor string
 	BackColor string
+
+	Shape        Shape
+	BorderColor  string
+	BorderWidth  int
+	CornerRadius int
+
+	Format Format
+
+	NameStyle     NameStyle
+	FontFallbacks []string
+
+	ColorStrategy ColorStrategy
 }
 
 func NewAvatar() *Avatar {
@@ -53,9 +62,11 @@ func NewAvatar() *Avatar {
 		Dpi:       int(dpi),
 		Spacer:    int(spacer),
 		TextX:     0,
-		TextY:     int(textY),
+		TextY:     0,
 		FontColor: "",
 		BackColor: "",
+		Shape:     ShapeSquare,
+		Format:    FormatPNG,
 	}
 }
 
@@ -77,7 +88,10 @@ func (a *Avatar) ConfigureColor(fontColor, backColor string) *Avatar {
 	return a
 }
 
-// ConfigurePosition configures avatar symbols position
+// ConfigurePosition adjusts the computed text position: a non-zero x shifts
+// the horizontally centered text by x pixels, and a non-zero y replaces the
+// computed baseline. Pass 0 for x and/or y to keep the automatic,
+// metrics-based centering on that axis.
 func (a *Avatar) ConfigurePosition(x, y int) *Avatar {
 	a.TextX, a.TextY = x, y
 	return a
@@ -95,11 +109,15 @@ func (a *Avatar) ToDiskCustom(initials, path, bgColor, fontColor string) error {
 
 // saveToDisk saves the image to disk
 func (a *Avatar) saveToDisk(initials, path, bgColor, fontColor string) error {
-	rgba, err := a.createAvatar(initials, bgColor, fontColor)
+	data, err := a.renderBytes(initials, bgColor, fontColor)
 	if err != nil {
 		return err
 	}
 
+	if filepath.Ext(path) == "" {
+		path += a.Format.extension()
+	}
+
 	// Save image to disk
 	out, err := os.Create(path)
 	if err != nil {
@@ -109,17 +127,11 @@ func (a *Avatar) saveToDisk(initials, path, bgColor, fontColor string) error {
 
 	b := bufio.NewWriter(out)
 
-	err = png.Encode(b, rgba)
-	if err != nil {
-		return err
-	}
-
-	err = b.Flush()
-	if err != nil {
+	if _, err := b.Write(data); err != nil {
 		return err
 	}
 
-	return nil
+	return b.Flush()
 }
 
 // ToHTTP sends the image to a http.ResponseWriter (as a PNG)
@@ -132,27 +144,22 @@ func (a *Avatar) ToHTTPCustom(initials, bgColor, fontColor string, w http.Respon
 	return a.saveToHTTP(initials, bgColor, fontColor, w)
 }
 
-// saveToHTTP sends the image to a http.ResponseWriter (as a PNG)
+// saveToHTTP sends the image to a http.ResponseWriter, encoded in a.Format
+// (PNG by default)
 func (a *Avatar) saveToHTTP(initials, bgColor, fontColor string, w http.ResponseWriter) error {
-	rgba, err := a.createAvatar(initials, bgColor, fontColor)
+	data, err := a.renderBytes(initials, bgColor, fontColor)
 	if err != nil {
 		return err
 	}
 
-	b := new(bytes.Buffer)
 	key := fmt.Sprintf("avatar%s", initials) // for Etag
 
-	err = png.Encode(b, rgba)
-	if err != nil {
-		return err
-	}
-
-	w.Header().Set("Content-Type", "image/png")
-	w.Header().Set("Content-Length", strconv.Itoa(len(b.Bytes())))
+	w.Header().Set("Content-Type", a.Format.contentType())
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
 	w.Header().Set("Cache-Control", "max-age=2592000") // 30 days
 	w.Header().Set("Etag", `"`+key+`"`)
 
-	if _, err := w.Write(b.Bytes()); err != nil {
+	if _, err := w.Write(data); err != nil {
 		return err
 	}
 
@@ -164,36 +171,55 @@ func (a *Avatar) cleanString(incoming string) string {
 		return incoming
 	}
 
+	switch a.NameStyle {
+	case StyleCJK:
+		return firstNGraphemes(strings.Join(strings.Fields(incoming), ""), 2)
+	case StyleFirstGrapheme:
+		return firstNGraphemes(incoming, 1)
+	default:
+		return a.cleanStringWestern(incoming)
+	}
+}
+
+// cleanStringWestern extracts the first grapheme cluster of each of the
+// first two whitespace-separated words, e.g. "François Müller" -> "FM".
+// A single word whose input has an even byte length instead yields its
+// first two grapheme clusters, upper-cased, e.g. "John" -> "JO".
+func (a *Avatar) cleanStringWestern(incoming string) string {
 	sb := strings.Builder{}
 	parts := strings.Fields(incoming)
 
-	if len(parts) == 1 && len(incoming) % 2 == 0 {
-		return strings.ToUpper(incoming)
+	if len(parts) == 1 && len(incoming)%2 == 0 {
+		return strings.ToUpper(firstNGraphemes(incoming, 2))
 	}
 
-	sb.WriteString(string([]rune(parts[0])[0]))
+	sb.WriteString(firstNGraphemes(parts[0], 1))
 
 	if len(parts) > 1 {
-		sb.WriteString(string([]rune(parts[1])[0]))
+		sb.WriteString(firstNGraphemes(parts[1], 1))
 	}
 
 	return sb.String()
 }
 
-func (a *Avatar) getFont() (*truetype.Font, error) {
-	// Read the font data.
-	fontBytes, err := ioutil.ReadFile(a.FontPath) //fmt.Sprintf("%s/%s", sourceDir, fontFaceName))
-	if err != nil {
-		return nil, err
-	}
+var imageCache sync.Map
 
-	return freetype.ParseFont(fontBytes)
+// cacheKey returns the imageCache key for rendering text with the given
+// effective bgColor/fontColor (the same-named createAvatar params, which
+// win over a.BackColor/a.FontColor). It folds in every piece of Avatar
+// configuration that affects the rendered pixels, not just the initials, so
+// two Avatars (or the same Avatar reconfigured) rendering the same initials
+// with a different shape, border, size, color, font or layout never
+// collide.
+func (a *Avatar) cacheKey(text, bgColor, fontColor string) string {
+	return fmt.Sprintf("%s|%dx%d|shape=%d|border=%s:%d:%d|bg=%s|fg=%s|strategy=%v|font=%s:%g:%d:%d|text=%d:%d|fallbacks=%s",
+		text, a.Width, a.Height, a.Shape, a.BorderColor, a.BorderWidth, a.CornerRadius,
+		bgColor, fontColor, a.ColorStrategy, a.FontPath, a.FontSize, a.Dpi, a.Spacer,
+		a.TextX, a.TextY, strings.Join(a.FontFallbacks, ","))
 }
 
-var imageCache sync.Map
-
-func (a *Avatar) getImage(initials string) *image.RGBA {
-	value, ok := imageCache.Load(initials)
+func (a *Avatar) getImage(key string) *image.RGBA {
+	value, ok := imageCache.Load(key)
 
 	if !ok {
 		return nil
@@ -206,8 +232,8 @@ func (a *Avatar) getImage(initials string) *image.RGBA {
 	return image
 }
 
-func (a *Avatar) setImage(initials string, image *image.RGBA) {
-	imageCache.Store(initials, image)
+func (a *Avatar) setImage(key string, image *image.RGBA) {
+	imageCache.Store(key, image)
 }
 
 func s2stringUtf(s string) []string {
@@ -223,6 +249,18 @@ func s2stringUtf(s string) []string {
 	return res
 }
 
+// graphemeClusters splits s into its grapheme clusters, keeping multi-rune
+// sequences (ZWJ emoji, flags, skin-tone modifiers) intact as a single
+// element instead of one element per rune.
+func graphemeClusters(s string) []string {
+	res := make([]string, 0, len(s))
+	gr := uniseg.NewGraphemes(s)
+	for gr.Next() {
+		res = append(res, gr.Str())
+	}
+	return res
+}
+
 func s2runesUtf(s string) []rune {
 	res := make([]rune, 0)
 	gr := uniseg.NewGraphemes(s)
@@ -241,32 +279,43 @@ func (a *Avatar) createAvatar(initials, bgColor, fontColor string) (*image.RGBA,
 	text := a.cleanString(initials)
 
 	// Check cache
-	cachedImage := a.getImage(text)
+	key := a.cacheKey(text, bgColor, fontColor)
+	cachedImage := a.getImage(key)
 	if cachedImage != nil {
 		return cachedImage, nil
 	}
 
 	// Load and get the font
-	f, err := a.getFont()
+	fontEntry, err := getFontEntry(a.FontPath, a.FontSize, a.Dpi, font.HintingFull)
 	if err != nil {
 		return nil, err
 	}
+	f := fontEntry.Font
+
+	// Setup the colors: background based on the first initial (or the
+	// configured ColorStrategy), text white (or contrast-adjusted black/white
+	// when a ColorStrategy is configured and FontColor is unset).
+	background := defaultColor(firstNGraphemes(text, 1))
+	if a.ColorStrategy != nil {
+		background = image.Uniform{C: a.ColorStrategy.BackgroundColor(text)}
+	}
+	if bgColor != "" {
+		c, err := parseHexColorFast(bgColor)
+		if err == nil {
+			background = image.Uniform{c}
+		}
+	}
 
-	// Setup the colors, text white, background based on first initial
 	textColor := image.White
+	if a.ColorStrategy != nil {
+		textColor = image.NewUniform(contrastForeground(background.C))
+	}
 	if fontColor != "" {
 		c, err := parseHexColorFast(fontColor)
 		if err == nil {
 			textColor = &image.Uniform{c}
 		}
 	}
-	background := defaultColor(text[0:1])
-	if bgColor != "" {
-		c, err := parseHexColorFast(bgColor)
-		if err == nil {
-			background = image.Uniform{c}
-		}
-	}
 
 	rgba := image.NewRGBA(image.Rect(0, 0, a.Width, a.Height))
 	draw.Draw(rgba, rgba.Bounds(), &background, image.ZP, draw.Src)
@@ -279,48 +328,98 @@ func (a *Avatar) createAvatar(initials, bgColor, fontColor string) (*image.RGBA,
 	c.SetSrc(textColor)
 	c.SetHinting(font.HintingFull)
 
-	// We need to convert the font into a "font.Face" so we can read the glyph
-	// info
-	to := truetype.Options{}
-	to.Size = a.FontSize
-	face := truetype.NewFace(f, &to)
-
-	// Calculate the widths and print to image
+	// Resolve a font entry for each grapheme cluster, falling back to
+	// a.FontFallbacks when the primary font has no glyph for it, and read
+	// the (cached) glyph advance and ink bounds from the resulting entry.
+	clusters := graphemeClusters(text)
+	entries := make([]*fontCacheEntry, len(clusters))
+	fonts := make([]*truetype.Font, len(clusters))
 	xPoints := []int{0, 0}
 	textWidths := []int{0, 0}
 
-	// Get the widths of the text characters
-	for i, char := range s2runesUtf(text) {
-		width, ok := face.GlyphAdvance(rune(char))
-		if !ok {
+	inkTop, inkBottom, haveInk := 0, 0, false
+
+	for i, cluster := range clusters {
+		r := []rune(cluster)[0]
+		entry, err := a.resolveFontEntry(r)
+		if err != nil {
 			return nil, err
 		}
+		entries[i] = entry
+		fonts[i] = entry.Font
 
+		width, ok := entry.GlyphAdvance(r)
+		if !ok {
+			return nil, fmt.Errorf("no glyph advance for %q", r)
+		}
 		textWidths[i] = int(width / 64)
+
+		if bounds, _, ok := entry.GlyphBounds(r); ok {
+			top, bottom := bounds.Min.Y.Round(), bounds.Max.Y.Round()
+			if !haveInk || top < inkTop {
+				inkTop = top
+			}
+			if !haveInk || bottom > inkBottom {
+				inkBottom = bottom
+			}
+			haveInk = true
+		}
 	}
 
-	// TODO need some tests for this
-	if len(textWidths) == 1 {
-		textWidths[1] = 0
+	// Horizontal gap between the two clusters: real kerning when they share
+	// a font, a.Spacer otherwise (kerning across two different faces is
+	// meaningless). A single cluster (StyleFirstGrapheme, emoji, ...) has no
+	// second glyph to space from, so there's no gap to add.
+	gap := 0
+	if len(clusters) > 1 {
+		gap = a.Spacer
+		if entries[0].Font == entries[1].Font {
+			gap = entries[0].Kern([]rune(clusters[0])[0], []rune(clusters[1])[0]).Round()
+		}
 	}
 
 	// Get the combined width of the characters
-	combinedWidth := textWidths[0] + a.Spacer + textWidths[1]
+	combinedWidth := textWidths[0] + gap + textWidths[1]
 
-	// Draw first character
-	xPoints[0] = int((a.Width - combinedWidth) / 2)
-	xPoints[1] = int(xPoints[0] + textWidths[0] + a.Spacer)
+	// Center horizontally, honoring a.TextX as an offset override
+	xPoints[0] = centerX(a.Width, combinedWidth, a.TextX)
+	xPoints[1] = xPoints[0] + textWidths[0] + gap
 
-	for i, char := range s2runesUtf(text) {
-		pt := freetype.Pt(xPoints[i], a.TextY)
-		_, err := c.DrawString(string(char), pt)
+	// Center vertically on the actual glyph ink, honoring a.TextY as an
+	// absolute baseline override
+	textY := centerY(a.Height, inkTop, inkBottom, a.TextY)
+
+	for i, cluster := range clusters {
+		pt := freetype.Pt(xPoints[i], textY)
+		c.SetFont(fonts[i])
+		_, err := c.DrawString(cluster, pt)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	if err := a.applyShape(rgba); err != nil {
+		return nil, err
+	}
+
 	// Cache it
-	a.setImage(text, rgba)
+	a.setImage(key, rgba)
 
 	return rgba, nil
 }
+
+// centerX returns the x position of the first cluster that centers a run of
+// combinedWidth pixels within width, shifted by offsetX.
+func centerX(width, combinedWidth, offsetX int) int {
+	return (width-combinedWidth)/2 + offsetX
+}
+
+// centerY returns the baseline y that vertically centers glyph ink spanning
+// [inkTop, inkBottom] within height, unless overrideY is non-zero, in which
+// case it's used directly as an absolute baseline.
+func centerY(height, inkTop, inkBottom, overrideY int) int {
+	if overrideY != 0 {
+		return overrideY
+	}
+	return height/2 - (inkTop+inkBottom)/2
+}
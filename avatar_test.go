@@ -0,0 +1,178 @@
+package avatar
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestCenterX(t *testing.T) {
+	tests := []struct {
+		name                          string
+		width, combinedWidth, offsetX int
+		want                          int
+	}{
+		{"even split", 500, 200, 0, 150},
+		{"odd remainder rounds down", 501, 200, 0, 150},
+		{"offset overrides nothing, just shifts", 500, 200, 20, 170},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := centerX(tt.width, tt.combinedWidth, tt.offsetX); got != tt.want {
+				t.Errorf("centerX(%d, %d, %d) = %d, want %d", tt.width, tt.combinedWidth, tt.offsetX, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCenterY(t *testing.T) {
+	tests := []struct {
+		name                                 string
+		height, inkTop, inkBottom, overrideY int
+		want                                 int
+	}{
+		{"centers on ink midpoint", 500, 100, 200, 0, 100},
+		{"override wins regardless of ink", 500, 100, 200, 320, 320},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := centerY(tt.height, tt.inkTop, tt.inkBottom, tt.overrideY); got != tt.want {
+				t.Errorf("centerY(%d, %d, %d, %d) = %d, want %d", tt.height, tt.inkTop, tt.inkBottom, tt.overrideY, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheKeyVariesWithConfig(t *testing.T) {
+	base := NewAvatar()
+	variants := []*Avatar{
+		NewAvatar(),
+		NewAvatar().ConfigureShape(ShapeCircle, "", 0),
+		NewAvatar().ConfigureShape(ShapeSquare, "#ff0000", 5),
+		NewAvatar().ConfigureSize(100, 100),
+		NewAvatar().ConfigureFont("Other.ttf", fontSize),
+		NewAvatar().ConfigureFont(defaultfontFace, 300),
+		NewAvatar().ConfigureColorStrategy(FNVPalette{Palette: []color.Color{color.Black}}),
+		func() *Avatar { a := NewAvatar(); a.Dpi = 144; return a }(),
+		func() *Avatar { a := NewAvatar(); a.Spacer = 5; return a }(),
+		NewAvatar().ConfigurePosition(10, 0),
+		NewAvatar().ConfigureFontFallbacks("Fallback.ttf"),
+	}
+
+	seen := map[string]bool{base.cacheKey("AB", "", ""): true}
+	for i, v := range variants[1:] {
+		key := v.cacheKey("AB", "", "")
+		if seen[key] {
+			t.Errorf("variant %d produced a cacheKey colliding with an earlier config: %q", i, key)
+		}
+		seen[key] = true
+	}
+}
+
+// TestCreateAvatarSingleGraphemeCentered renders a single grapheme cluster
+// (StyleFirstGrapheme, or any other style that yields one) and checks the
+// glyph's ink is horizontally centered on the canvas. combinedWidth must not include
+// a.Spacer when there's no second cluster to space from, or the glyph
+// renders visibly off-center.
+func TestCreateAvatarSingleGraphemeCentered(t *testing.T) {
+	a := NewAvatar().ConfigureNameStyle(StyleFirstGrapheme)
+	img, err := a.createAvatar("A", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounds := img.Bounds()
+	bg := img.At(0, 0)
+	minX, maxX := -1, -1
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			if img.At(x, y) != bg {
+				if minX == -1 {
+					minX = x
+				}
+				maxX = x
+			}
+		}
+	}
+	if minX == -1 {
+		t.Fatal("no glyph ink found in rendered avatar")
+	}
+
+	glyphCenter := (minX + maxX) / 2
+	canvasCenter := a.Width / 2
+	if d := glyphCenter - canvasCenter; d < -2 || d > 2 {
+		t.Errorf("glyph center %d is %dpx off canvas center %d, want within 2px", glyphCenter, d, canvasCenter)
+	}
+}
+
+// TestCreateAvatarFontSizeCacheMiss guards against the cacheKey collision
+// this fixes directly: two Avatars identical except FontSize must render
+// (and cache) distinct images, not share a cache hit keyed only on text,
+// shape, border, size, color, strategy and font path.
+func TestCreateAvatarFontSizeCacheMiss(t *testing.T) {
+	small := NewAvatar().ConfigureFont(defaultfontFace, 100)
+	large := NewAvatar().ConfigureFont(defaultfontFace, 300)
+
+	img1, err := small.createAvatar("AB", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img2, err := large.createAvatar("AB", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b1, b2 bytes.Buffer
+	if err := png.Encode(&b1, img1); err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(&b2, img2); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(b1.Bytes(), b2.Bytes()) {
+		t.Fatal("different FontSize produced identical cached image")
+	}
+}
+
+// TestCleanStringWesternSingleWord guards against the single-word branch
+// returning the whole word for any even-length input (e.g. "John" ->
+// "JOHN", 4 grapheme clusters) instead of at most 2.
+func TestCleanStringWesternSingleWord(t *testing.T) {
+	a := NewAvatar()
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"John", "JO"},
+		{"Mary", "MA"},
+		{"Anna", "AN"},
+		{"Kate", "KA"},
+		{"Ryan", "RY"},
+		{"Al", "AL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := a.cleanString(tt.name)
+			if got != tt.want {
+				t.Errorf("cleanString(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+			if n := len(graphemeClusters(got)); n > 2 {
+				t.Errorf("cleanString(%q) = %q has %d grapheme clusters, want at most 2", tt.name, got, n)
+			}
+		})
+	}
+}
+
+// TestCreateAvatarSingleFirstName renders common single first names
+// end-to-end: createAvatar's xPoints/textWidths are hardcoded to 2 slots, so
+// any cleanString result with more than 2 grapheme clusters panics with an
+// out-of-range index.
+func TestCreateAvatarSingleFirstName(t *testing.T) {
+	a := NewAvatar()
+	for _, name := range []string{"John", "Mary", "Anna", "Kate", "Ryan"} {
+		if _, err := a.createAvatar(name, "", ""); err != nil {
+			t.Errorf("createAvatar(%q): %v", name, err)
+		}
+	}
+}
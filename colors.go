@@ -0,0 +1,197 @@
+package avatar
+
+import (
+	"errors"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// defaultPalette is the fallback set of background colors used when no
+// ColorStrategy is configured, indexed by the first initial.
+var defaultPalette = []color.RGBA{
+	{R: 0xE5, G: 0x73, B: 0x73, A: 0xff},
+	{R: 0xF0, G: 0x62, B: 0x92, A: 0xff},
+	{R: 0xBA, G: 0x68, B: 0xC8, A: 0xff},
+	{R: 0x95, G: 0x75, B: 0xCD, A: 0xff},
+	{R: 0x79, G: 0x86, B: 0xCB, A: 0xff},
+	{R: 0x64, G: 0xB5, B: 0xF6, A: 0xff},
+	{R: 0x4F, G: 0xC3, B: 0xF7, A: 0xff},
+	{R: 0x4D, G: 0xD0, B: 0xE1, A: 0xff},
+	{R: 0x4D, G: 0xB6, B: 0xAC, A: 0xff},
+	{R: 0x81, G: 0xC7, B: 0x84, A: 0xff},
+	{R: 0xAE, G: 0xD5, B: 0x81, A: 0xff},
+	{R: 0xFF, G: 0xD5, B: 0x4F, A: 0xff},
+	{R: 0xFF, G: 0xB7, B: 0x4D, A: 0xff},
+	{R: 0xFF, G: 0x8A, B: 0x65, A: 0xff},
+	{R: 0xA1, G: 0x88, B: 0x7F, A: 0xff},
+	{R: 0x90, G: 0xA4, B: 0xAE, A: 0xff},
+}
+
+// defaultColor picks a background color for initial (its first letter) from
+// defaultPalette.
+func defaultColor(initial string) image.Uniform {
+	idx := 0
+	if r := []rune(strings.ToUpper(initial)); len(r) > 0 {
+		idx = int(r[0]) % len(defaultPalette)
+	}
+	return image.Uniform{C: defaultPalette[idx]}
+}
+
+// parseHexColorFast parses a "#rgb" or "#rrggbb" string into a color.RGBA.
+func parseHexColorFast(s string) (c color.RGBA, err error) {
+	c.A = 0xff
+
+	hexToByte := func(b byte) byte {
+		switch {
+		case b >= '0' && b <= '9':
+			return b - '0'
+		case b >= 'a' && b <= 'f':
+			return b - 'a' + 10
+		case b >= 'A' && b <= 'F':
+			return b - 'A' + 10
+		}
+		return 0
+	}
+
+	switch len(s) {
+	case 7:
+		c.R = hexToByte(s[1])<<4 + hexToByte(s[2])
+		c.G = hexToByte(s[3])<<4 + hexToByte(s[4])
+		c.B = hexToByte(s[5])<<4 + hexToByte(s[6])
+	case 4:
+		c.R = hexToByte(s[1]) * 17
+		c.G = hexToByte(s[2]) * 17
+		c.B = hexToByte(s[3]) * 17
+	default:
+		err = errors.New("avatar: invalid hex color " + s)
+	}
+
+	return c, err
+}
+
+// ColorStrategy picks a deterministic background color for a set of
+// cleaned initials. The foreground is always derived separately, from the
+// chosen background's WCAG contrast ratio.
+type ColorStrategy interface {
+	BackgroundColor(initials string) color.Color
+}
+
+// ConfigureColorStrategy configures the ColorStrategy used to pick the
+// avatar's background (and, when FontColor is unset, its contrasting
+// foreground). Pass nil to restore the built-in first-letter palette.
+func (a *Avatar) ConfigureColorStrategy(strategy ColorStrategy) *Avatar {
+	a.ColorStrategy = strategy
+	return a
+}
+
+// FNVPalette hashes the full initials string with FNV-64a and indexes into
+// Palette, so that e.g. "AB" and "AC" land on different colors instead of
+// both keying off their shared first letter.
+type FNVPalette struct {
+	Palette []color.Color
+}
+
+// BackgroundColor implements ColorStrategy.
+func (p FNVPalette) BackgroundColor(initials string) color.Color {
+	if len(p.Palette) == 0 {
+		return color.Black
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(initials))
+
+	return p.Palette[h.Sum64()%uint64(len(p.Palette))]
+}
+
+// HSLGenerator derives a hue from the FNV-64a hash of the initials and
+// combines it with a fixed saturation/lightness to produce a visually
+// balanced background. Saturation and Lightness are in [0, 1]; zero values
+// fall back to 0.55 and 0.5 respectively.
+type HSLGenerator struct {
+	Saturation float64
+	Lightness  float64
+}
+
+// BackgroundColor implements ColorStrategy.
+func (g HSLGenerator) BackgroundColor(initials string) color.Color {
+	h := fnv.New64a()
+	h.Write([]byte(initials))
+	hue := float64(h.Sum64() % 360)
+
+	s, l := g.Saturation, g.Lightness
+	if s == 0 {
+		s = 0.55
+	}
+	if l == 0 {
+		l = 0.5
+	}
+
+	return hslToRGBA(hue, s, l)
+}
+
+// hslToRGBA converts HSL (hue in degrees, saturation/lightness in [0, 1])
+// to an opaque color.RGBA.
+func hslToRGBA(hue, s, l float64) color.RGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case hue < 60:
+		r, g, b = c, x, 0
+	case hue < 120:
+		r, g, b = x, c, 0
+	case hue < 180:
+		r, g, b = 0, c, x
+	case hue < 240:
+		r, g, b = 0, x, c
+	case hue < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+
+	return color.RGBA{
+		R: uint8((r + m) * 255),
+		G: uint8((g + m) * 255),
+		B: uint8((b + m) * 255),
+		A: 0xff,
+	}
+}
+
+// contrastForeground picks black or white, whichever has the higher WCAG
+// contrast ratio against bg.
+func contrastForeground(bg color.Color) color.Color {
+	bgLum := relativeLuminance(bg)
+	if contrastRatio(bgLum, relativeLuminance(color.Black)) >= contrastRatio(bgLum, relativeLuminance(color.White)) {
+		return color.Black
+	}
+	return color.White
+}
+
+// relativeLuminance computes the WCAG relative luminance of c, in [0, 1].
+func relativeLuminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	lin := func(v uint32) float64 {
+		s := float64(v) / 65535
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+
+	return 0.2126*lin(r) + 0.7152*lin(g) + 0.0722*lin(b)
+}
+
+// contrastRatio computes the WCAG contrast ratio between two relative
+// luminances.
+func contrastRatio(l1, l2 float64) float64 {
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	return (l1 + 0.05) / (l2 + 0.05)
+}
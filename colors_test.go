@@ -0,0 +1,58 @@
+package avatar
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestContrastForegroundPicksBlackOnLight(t *testing.T) {
+	if got := contrastForeground(color.White); got != color.Black {
+		t.Errorf("contrastForeground(white) = %v, want black", got)
+	}
+}
+
+func TestContrastForegroundPicksWhiteOnDark(t *testing.T) {
+	if got := contrastForeground(color.Black); got != color.White {
+		t.Errorf("contrastForeground(black) = %v, want white", got)
+	}
+}
+
+func TestFNVPaletteIsDeterministicAndVaries(t *testing.T) {
+	palette := []color.Color{color.RGBA{R: 1, A: 0xff}, color.RGBA{G: 1, A: 0xff}, color.RGBA{B: 1, A: 0xff}}
+	p := FNVPalette{Palette: palette}
+
+	first := p.BackgroundColor("AB")
+	if second := p.BackgroundColor("AB"); first != second {
+		t.Errorf("BackgroundColor(%q) is not deterministic: %v != %v", "AB", first, second)
+	}
+	if ab, ac := p.BackgroundColor("AB"), p.BackgroundColor("AC"); ab == ac {
+		t.Errorf("BackgroundColor(%q) and BackgroundColor(%q) collided on %v, want distinct colors for distinct initials", "AB", "AC", ab)
+	}
+}
+
+func TestFNVPaletteEmptyFallsBackToBlack(t *testing.T) {
+	p := FNVPalette{}
+	if got := p.BackgroundColor("AB"); got != color.Black {
+		t.Errorf("BackgroundColor with empty palette = %v, want black", got)
+	}
+}
+
+func TestHSLGeneratorIsDeterministicAndVaries(t *testing.T) {
+	g := HSLGenerator{}
+
+	first := g.BackgroundColor("AB")
+	if second := g.BackgroundColor("AB"); first != second {
+		t.Errorf("BackgroundColor(%q) is not deterministic: %v != %v", "AB", first, second)
+	}
+	if ab, cd := g.BackgroundColor("AB"), g.BackgroundColor("CD"); ab == cd {
+		t.Errorf("BackgroundColor(%q) and BackgroundColor(%q) collided on %v, want distinct hues", "AB", "CD", ab)
+	}
+}
+
+func TestHSLGeneratorDefaultsSaturationAndLightness(t *testing.T) {
+	defaulted := HSLGenerator{}.BackgroundColor("AB")
+	explicit := HSLGenerator{Saturation: 0.55, Lightness: 0.5}.BackgroundColor("AB")
+	if defaulted != explicit {
+		t.Errorf("zero-value HSLGenerator = %v, want same as explicit 0.55/0.5 = %v", defaulted, explicit)
+	}
+}
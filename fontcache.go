@@ -0,0 +1,106 @@
+package avatar
+
+import (
+	"sync"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// fontCacheKey identifies a parsed font plus the face options built from it,
+// so that the same (font, size, DPI, hinting) combination is only ever
+// parsed once per process.
+type fontCacheKey struct {
+	path    string
+	size    float64
+	dpi     int
+	hinting font.Hinting
+}
+
+// fontCacheEntry holds a parsed font shared across every caller, plus a
+// cache of glyph advances computed for its (size, DPI, hinting) combination
+// so repeated GlyphAdvance lookups for the same rune skip the face lookup
+// entirely. font.Face itself is documented as unsafe for concurrent use (its
+// methods mutate a shared glyphBuf/indexCache), so no Face is stored here —
+// each call builds its own via newFace, which is cheap.
+type fontCacheEntry struct {
+	Font        *truetype.Font
+	faceOptions truetype.Options
+
+	advances sync.Map // rune -> fixed.Int26_6
+}
+
+// newFace builds a fresh font.Face for this entry's font and options. Faces
+// must not be shared across goroutines, so callers get their own every time.
+func (e *fontCacheEntry) newFace() font.Face {
+	return truetype.NewFace(e.Font, &e.faceOptions)
+}
+
+// GlyphAdvance returns the advance width for r, computing and caching it on
+// the first call.
+func (e *fontCacheEntry) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	if v, ok := e.advances.Load(r); ok {
+		return v.(fixed.Int26_6), true
+	}
+
+	width, ok := e.newFace().GlyphAdvance(r)
+	if ok {
+		e.advances.Store(r, width)
+	}
+
+	return width, ok
+}
+
+// GlyphBounds returns the glyph bounds and advance width for r, on a
+// private face so concurrent callers never share mutable face state.
+func (e *fontCacheEntry) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	return e.newFace().GlyphBounds(r)
+}
+
+// Kern returns the kerning adjustment between r0 and r1, on a private face.
+func (e *fontCacheEntry) Kern(r0, r1 rune) fixed.Int26_6 {
+	return e.newFace().Kern(r0, r1)
+}
+
+var fontRegistry sync.Map // fontCacheKey -> *fontCacheEntry
+
+// getFontEntry returns the fontCacheEntry for (path, size, dpi, hinting),
+// parsing the font file on the first call and reusing it on every
+// subsequent one.
+func getFontEntry(path string, size float64, dpi int, hinting font.Hinting) (*fontCacheEntry, error) {
+	key := fontCacheKey{path: path, size: size, dpi: dpi, hinting: hinting}
+
+	if v, ok := fontRegistry.Load(key); ok {
+		return v.(*fontCacheEntry), nil
+	}
+
+	fnt, err := loadFont(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &fontCacheEntry{
+		Font: fnt,
+		faceOptions: truetype.Options{
+			Size:    size,
+			DPI:     float64(dpi),
+			Hinting: hinting,
+		},
+	}
+
+	actual, _ := fontRegistry.LoadOrStore(key, entry)
+	return actual.(*fontCacheEntry), nil
+}
+
+// PreloadFont parses path and warms the registry for each of sizes (at the
+// package's default DPI and hinting) ahead of the first request, so servers
+// don't pay the parse cost on a hot path.
+func PreloadFont(path string, sizes ...float64) error {
+	for _, size := range sizes {
+		if _, err := getFontEntry(path, size, int(dpi), font.HintingFull); err != nil {
+			return err
+		}
+	}
+	return nil
+}
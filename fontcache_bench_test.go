@@ -0,0 +1,64 @@
+package avatar
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/image/font"
+)
+
+// uniqueInitialsWorkload returns up to n distinct two-letter initials, so a
+// benchmark driven by it never hits imageCache and always exercises font
+// loading and glyph measurement.
+func uniqueInitialsWorkload(n int) []string {
+	const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	initials := make([]string, 0, n)
+	for i := 0; i < len(letters) && len(initials) < n; i++ {
+		for j := 0; j < len(letters) && len(initials) < n; j++ {
+			initials = append(initials, fmt.Sprintf("%c %c", letters[i], letters[j]))
+		}
+	}
+	return initials
+}
+
+// BenchmarkCreateAvatar_UniqueInitials renders many unique initials, each
+// forcing a fresh createAvatar call since imageCache only helps on repeats.
+// It's the workload the fontCacheEntry registry is meant for: without it,
+// every call would re-read and re-parse FontPath from disk. The workload
+// only has 256 distinct entries, so once b.N exceeds that the loop evicts
+// each entry's imageCache hit right before re-rendering it, keeping every
+// iteration a genuine cache miss regardless of b.N.
+func BenchmarkCreateAvatar_UniqueInitials(b *testing.B) {
+	a := NewAvatar()
+	workload := uniqueInitialsWorkload(256)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		initials := workload[i%len(workload)]
+		text := a.cleanString(initials)
+		imageCache.Delete(a.cacheKey(text, a.BackColor, a.FontColor))
+
+		if _, err := a.Render(initials); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetFontEntry_WarmRegistry isolates the registry lookup itself:
+// once PreloadFont has warmed it, repeated calls for the same
+// (font, size, DPI, hinting) key are a single sync.Map load rather than a
+// disk read and truetype.ParseFont.
+func BenchmarkGetFontEntry_WarmRegistry(b *testing.B) {
+	a := NewAvatar()
+	if err := PreloadFont(a.FontPath, a.FontSize); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := getFontEntry(a.FontPath, a.FontSize, a.Dpi, font.HintingFull); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
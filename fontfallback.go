@@ -0,0 +1,54 @@
+package avatar
+
+import (
+	"io/ioutil"
+
+	"github.com/golang/freetype"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+)
+
+// ConfigureFontFallbacks configures additional font files to try, in order,
+// when the primary font (a.FontPath) has no glyph for a grapheme — e.g. CJK
+// or emoji fonts backing a Latin primary font.
+func (a *Avatar) ConfigureFontFallbacks(paths ...string) *Avatar {
+	a.FontFallbacks = paths
+	return a
+}
+
+// resolveFontEntry returns the cached fontCacheEntry, starting with
+// a.FontPath, for the first font that has a glyph for r. If none of the
+// configured fallbacks have one either, the primary entry is returned so
+// rendering can still proceed (DrawString will fall back to the font's
+// .notdef glyph).
+func (a *Avatar) resolveFontEntry(r rune) (*fontCacheEntry, error) {
+	primary, err := getFontEntry(a.FontPath, a.FontSize, a.Dpi, font.HintingFull)
+	if err != nil {
+		return nil, err
+	}
+	if primary.Font.Index(r) != 0 {
+		return primary, nil
+	}
+
+	for _, path := range a.FontFallbacks {
+		entry, err := getFontEntry(path, a.FontSize, a.Dpi, font.HintingFull)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Font.Index(r) != 0 {
+			return entry, nil
+		}
+	}
+
+	return primary, nil
+}
+
+// loadFont reads and parses a TTF font file from disk.
+func loadFont(path string) (*truetype.Font, error) {
+	fontBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return freetype.ParseFont(fontBytes)
+}
@@ -0,0 +1,202 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	"github.com/chai2010/webp"
+)
+
+// Format selects the encoding used when rendering or saving an avatar.
+type Format int
+
+const (
+	// FormatPNG encodes the rasterized avatar as PNG (default).
+	FormatPNG Format = iota
+	// FormatJPEG encodes the rasterized avatar as JPEG.
+	FormatJPEG
+	// FormatWebP encodes the rasterized avatar as WebP.
+	FormatWebP
+	// FormatSVG emits a vector SVG document instead of rasterizing.
+	FormatSVG
+)
+
+// ConfigureFormat configures the output format used by ToDisk, ToHTTP,
+// Render and RenderBytes.
+func (a *Avatar) ConfigureFormat(format Format) *Avatar {
+	a.Format = format
+	return a
+}
+
+func (format Format) contentType() string {
+	switch format {
+	case FormatJPEG:
+		return "image/jpeg"
+	case FormatWebP:
+		return "image/webp"
+	case FormatSVG:
+		return "image/svg+xml"
+	default:
+		return "image/png"
+	}
+}
+
+func (format Format) extension() string {
+	switch format {
+	case FormatJPEG:
+		return ".jpg"
+	case FormatWebP:
+		return ".webp"
+	case FormatSVG:
+		return ".svg"
+	default:
+		return ".png"
+	}
+}
+
+// Render rasterizes initials and returns the resulting image, regardless of
+// a.Format, so callers can embed the avatar in a composite image.
+func (a *Avatar) Render(initials string) (image.Image, error) {
+	return a.createAvatar(initials, a.BackColor, a.FontColor)
+}
+
+// RenderBytes rasterizes (or, for FormatSVG, vectorizes) initials and
+// encodes the result in a.Format.
+func (a *Avatar) RenderBytes(initials string) ([]byte, error) {
+	return a.renderBytes(initials, a.BackColor, a.FontColor)
+}
+
+func (a *Avatar) renderBytes(initials, bgColor, fontColor string) ([]byte, error) {
+	if a.Format == FormatSVG {
+		return a.renderSVG(initials, bgColor, fontColor)
+	}
+
+	rgba, err := a.createAvatar(initials, bgColor, fontColor)
+	if err != nil {
+		return nil, err
+	}
+
+	b := new(bytes.Buffer)
+	switch a.Format {
+	case FormatJPEG:
+		// JPEG has no alpha channel; flatten onto an opaque white canvas first.
+		flat := image.NewRGBA(rgba.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.White, image.ZP, draw.Src)
+		draw.Draw(flat, flat.Bounds(), rgba, image.ZP, draw.Over)
+		err = jpeg.Encode(b, flat, &jpeg.Options{Quality: jpeg.DefaultQuality})
+	case FormatWebP:
+		err = webp.Encode(b, rgba, &webp.Options{Lossless: true})
+	default:
+		err = png.Encode(b, rgba)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// renderSVG emits a vector <svg> document using the configured shape and
+// colors, without rasterizing the text. Colors are parsed the same way the
+// raster path parses them (parseHexColorFast, falling back to the default
+// on anything else) and text content is XML-escaped, so attacker-controlled
+// bgColor/fontColor/initials (the expected inputs of ToHTTPCustom behind a
+// web handler) can't break out of an attribute or inject markup.
+func (a *Avatar) renderSVG(initials, bgColor, fontColor string) ([]byte, error) {
+	text := a.cleanString(initials)
+
+	background := defaultColor(firstNGraphemes(text, 1)).C
+	if a.ColorStrategy != nil {
+		background = a.ColorStrategy.BackgroundColor(text)
+	}
+	if bgColor != "" {
+		if c, err := parseHexColorFast(bgColor); err == nil {
+			background = c
+		}
+	}
+	foreground := color.Color(color.White)
+	if a.ColorStrategy != nil {
+		foreground = contrastForeground(background)
+	}
+	if fontColor != "" {
+		if c, err := parseHexColorFast(fontColor); err == nil {
+			foreground = c
+		}
+	}
+
+	b := new(bytes.Buffer)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		a.Width, a.Height, a.Width, a.Height)
+
+	switch a.Shape {
+	case ShapeCircle:
+		cx, cy, r := a.Width/2, a.Height/2, minInt(a.Width, a.Height)/2
+		fmt.Fprintf(b, `<circle cx="%d" cy="%d" r="%d" fill="%s"/>`, cx, cy, r, hexColor(background))
+	case ShapeRoundedRect:
+		fmt.Fprintf(b, `<rect width="%d" height="%d" rx="%d" ry="%d" fill="%s"/>`,
+			a.Width, a.Height, a.cornerRadius(), a.cornerRadius(), hexColor(background))
+	default:
+		fmt.Fprintf(b, `<rect width="%d" height="%d" fill="%s"/>`, a.Width, a.Height, hexColor(background))
+	}
+
+	if a.BorderColor != "" && a.BorderWidth > 0 {
+		if borderColor, err := parseHexColorFast(a.BorderColor); err == nil {
+			switch a.Shape {
+			case ShapeCircle:
+				cx, cy, r := a.Width/2, a.Height/2, minInt(a.Width, a.Height)/2-a.BorderWidth/2
+				fmt.Fprintf(b, `<circle cx="%d" cy="%d" r="%d" fill="none" stroke="%s" stroke-width="%d"/>`,
+					cx, cy, r, hexColor(borderColor), a.BorderWidth)
+			case ShapeRoundedRect:
+				fmt.Fprintf(b, `<rect x="%d" y="%d" width="%d" height="%d" rx="%d" ry="%d" fill="none" stroke="%s" stroke-width="%d"/>`,
+					a.BorderWidth/2, a.BorderWidth/2, a.Width-a.BorderWidth, a.Height-a.BorderWidth,
+					a.cornerRadius(), a.cornerRadius(), hexColor(borderColor), a.BorderWidth)
+			default:
+				fmt.Fprintf(b, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="%s" stroke-width="%d"/>`,
+					a.BorderWidth/2, a.BorderWidth/2, a.Width-a.BorderWidth, a.Height-a.BorderWidth, hexColor(borderColor), a.BorderWidth)
+			}
+		}
+	}
+
+	// a.Dpi's default of 72 makes FreeType's point-based a.FontSize equal to
+	// pixels (size_px = size_pt * dpi/72), the same em box the raster path
+	// draws into at a.FontSize. Use it un-halved here so FormatSVG's text
+	// renders at the same size as the rasterized formats for the same
+	// Avatar config, instead of an arbitrary fraction of it.
+	fontFamily := strings.TrimSuffix(filepath.Base(a.FontPath), filepath.Ext(a.FontPath))
+	fmt.Fprintf(b, `<text x="50%%" y="50%%" font-family="%s" font-size="%d" fill="%s" text-anchor="middle" dominant-baseline="central">`,
+		xmlEscape(fontFamily), int(a.FontSize), hexColor(foreground))
+	xml.EscapeText(b, []byte(text))
+	b.WriteString(`</text>`)
+
+	b.WriteString(`</svg>`)
+
+	return b.Bytes(), nil
+}
+
+// hexColor renders c as a "#rrggbb" string for embedding in SVG output.
+func hexColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", uint8(r>>8), uint8(g>>8), uint8(b>>8))
+}
+
+// xmlEscape escapes s for safe use as XML attribute or element content.
+func xmlEscape(s string) string {
+	b := new(bytes.Buffer)
+	xml.EscapeText(b, []byte(s))
+	return b.String()
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,94 @@
+package avatar
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFormatContentTypeAndExtension(t *testing.T) {
+	tests := []struct {
+		format   Format
+		wantType string
+		wantExt  string
+	}{
+		{FormatPNG, "image/png", ".png"},
+		{FormatJPEG, "image/jpeg", ".jpg"},
+		{FormatWebP, "image/webp", ".webp"},
+		{FormatSVG, "image/svg+xml", ".svg"},
+	}
+	for _, tt := range tests {
+		if got := tt.format.contentType(); got != tt.wantType {
+			t.Errorf("Format(%d).contentType() = %q, want %q", tt.format, got, tt.wantType)
+		}
+		if got := tt.format.extension(); got != tt.wantExt {
+			t.Errorf("Format(%d).extension() = %q, want %q", tt.format, got, tt.wantExt)
+		}
+	}
+}
+
+func TestRenderSVGIsWellFormedXML(t *testing.T) {
+	a := NewAvatar().ConfigureFormat(FormatSVG).ConfigureShape(ShapeCircle, "#00ff00", 5)
+	data, err := a.RenderBytes("AB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := xml.Unmarshal(data, new(struct {
+		XMLName xml.Name
+	})); err != nil {
+		t.Fatalf("renderSVG produced invalid XML: %v\n%s", err, data)
+	}
+}
+
+func TestRenderSVGEscapesInitials(t *testing.T) {
+	a := NewAvatar().ConfigureFormat(FormatSVG)
+	data, err := a.RenderBytes("<script>&")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "<script>") {
+		t.Fatalf("renderSVG did not escape injected markup: %s", data)
+	}
+	if err := xml.Unmarshal(data, new(struct {
+		XMLName xml.Name
+	})); err != nil {
+		t.Fatalf("renderSVG produced invalid XML for hostile initials: %v\n%s", err, data)
+	}
+}
+
+// TestRenderSVGFontSizeMatchesRaster guards against renderSVG scaling
+// a.FontSize by an arbitrary, undocumented factor: FormatSVG and the
+// rasterized formats should render initials at the same size for the same
+// Avatar config, so the <text> font-size must equal a.FontSize (DPI=72
+// makes FreeType's point-based FontSize equal pixels, matching the em box
+// the raster path draws into).
+func TestRenderSVGFontSizeMatchesRaster(t *testing.T) {
+	a := NewAvatar().ConfigureFormat(FormatSVG).ConfigureFont(defaultfontFace, 120)
+	data, err := a.RenderBytes("AB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := fmt.Sprintf(`font-size="%d"`, int(a.FontSize))
+	if !strings.Contains(string(data), want) {
+		t.Errorf("renderSVG output missing %s (want font-size matching FontSize un-halved): %s", want, data)
+	}
+}
+
+func TestRenderSVGShapes(t *testing.T) {
+	for _, shape := range []Shape{ShapeSquare, ShapeCircle, ShapeRoundedRect} {
+		a := NewAvatar().ConfigureFormat(FormatSVG).ConfigureShape(shape, "", 0)
+		data, err := a.RenderBytes("AB")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := xml.Unmarshal(data, new(struct {
+			XMLName xml.Name
+		})); err != nil {
+			t.Fatalf("shape %d produced invalid XML: %v\n%s", shape, err, data)
+		}
+	}
+}
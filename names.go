@@ -0,0 +1,44 @@
+package avatar
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// NameStyle selects how initials are extracted from the input string.
+type NameStyle int
+
+const (
+	// StyleWestern splits on whitespace and takes the first grapheme
+	// cluster of the first two words, e.g. "François Müller" -> "FM". A
+	// single word whose input has an even byte length yields its first two
+	// grapheme clusters, upper-cased, e.g. "John" -> "JO".
+	StyleWestern NameStyle = iota
+	// StyleCJK strips whitespace and takes the first two grapheme
+	// clusters of the string, e.g. "山田太郎" -> "山田".
+	StyleCJK
+	// StyleFirstGrapheme takes only the first grapheme cluster of the
+	// whole string, treating multi-rune sequences (ZWJ emoji, flags,
+	// skin-tone modifiers) as a single glyph.
+	StyleFirstGrapheme
+)
+
+// ConfigureNameStyle configures how initials are extracted from the name
+// passed to ToDisk/ToHTTP/Render.
+func (a *Avatar) ConfigureNameStyle(style NameStyle) *Avatar {
+	a.NameStyle = style
+	return a
+}
+
+// firstNGraphemes returns the first n grapheme clusters of s, concatenated,
+// so multi-rune sequences (ZWJ emoji, flags, skin-tone modifiers) are kept
+// intact rather than split into individual runes.
+func firstNGraphemes(s string, n int) string {
+	sb := strings.Builder{}
+	gr := uniseg.NewGraphemes(s)
+	for i := 0; i < n && gr.Next(); i++ {
+		sb.WriteString(gr.Str())
+	}
+	return sb.String()
+}
@@ -0,0 +1,149 @@
+package avatar
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// opaqueRGBA returns a fully opaque white canvas of the given size, the
+// state applyShape receives right after the background fill and before
+// masking/bordering.
+func opaqueRGBA(width, height int) *image.RGBA {
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range rgba.Pix {
+		rgba.Pix[i] = 0xff
+	}
+	return rgba
+}
+
+func TestApplyShapeSquareLeavesCornersOpaque(t *testing.T) {
+	a := NewAvatar().ConfigureShape(ShapeSquare, "", 0)
+	rgba := opaqueRGBA(a.Width, a.Height)
+
+	if err := a.applyShape(rgba); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, alpha := rgba.At(0, 0).RGBA(); alpha>>8 != 0xff {
+		t.Errorf("ShapeSquare corner alpha = %d, want opaque", alpha>>8)
+	}
+}
+
+func TestApplyShapeCircleMasksCorners(t *testing.T) {
+	a := NewAvatar().ConfigureShape(ShapeCircle, "", 0)
+	rgba := opaqueRGBA(a.Width, a.Height)
+
+	if err := a.applyShape(rgba); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, alpha := rgba.At(0, 0).RGBA(); alpha != 0 {
+		t.Errorf("ShapeCircle corner alpha = %d, want fully transparent", alpha)
+	}
+	if _, _, _, alpha := rgba.At(a.Width/2, a.Height/2).RGBA(); alpha>>8 != 0xff {
+		t.Errorf("ShapeCircle center alpha = %d, want opaque", alpha>>8)
+	}
+}
+
+func TestApplyShapeRoundedRectMasksCorners(t *testing.T) {
+	a := NewAvatar().ConfigureShape(ShapeRoundedRect, "", 0)
+	a.ConfigureCornerRadius(64)
+	rgba := opaqueRGBA(a.Width, a.Height)
+
+	if err := a.applyShape(rgba); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, alpha := rgba.At(0, 0).RGBA(); alpha != 0 {
+		t.Errorf("ShapeRoundedRect corner alpha = %d, want fully transparent", alpha)
+	}
+	if _, _, _, alpha := rgba.At(a.Width/2, a.Height/2).RGBA(); alpha>>8 != 0xff {
+		t.Errorf("ShapeRoundedRect center alpha = %d, want opaque", alpha>>8)
+	}
+}
+
+func TestApplyShapeDrawsBorder(t *testing.T) {
+	a := NewAvatar().ConfigureShape(ShapeCircle, "#ff0000", 10)
+	rgba := opaqueRGBA(a.Width, a.Height)
+
+	if err := a.applyShape(rgba); err != nil {
+		t.Fatal(err)
+	}
+
+	cx, cy, r := a.Width/2, a.Height/2, a.Width/2
+	borderColor := rgba.At(cx, cy-r+1)
+	wantR, wantG, wantB, _ := color.RGBA{R: 0xff, A: 0xff}.RGBA()
+	gotR, gotG, gotB, _ := borderColor.RGBA()
+	if gotR>>8 != wantR>>8 || gotG>>8 != wantG>>8 || gotB>>8 != wantB>>8 {
+		t.Errorf("border pixel color = %v, want red", borderColor)
+	}
+
+	if _, _, _, alpha := rgba.At(cx, cy).RGBA(); alpha>>8 != 0xff {
+		t.Errorf("fill inside the border should be left alone, alpha = %d", alpha>>8)
+	}
+}
+
+// TestApplyShapeCircleBorderIsAngleInvariant guards against isBorderPixel
+// approximating edge distance with cardinal-direction coverage probes,
+// which stroked a visibly thinner ring on the diagonals than on the
+// cardinal axes.
+func TestApplyShapeCircleBorderIsAngleInvariant(t *testing.T) {
+	a := NewAvatar().ConfigureShape(ShapeCircle, "#ff0000", 20)
+	rgba := opaqueRGBA(a.Width, a.Height)
+	if err := a.applyShape(rgba); err != nil {
+		t.Fatal(err)
+	}
+
+	cx, cy := float64(a.Width)/2, float64(a.Height)/2
+	isRed := func(x, y int) bool {
+		r, g, b, alpha := rgba.At(x, y).RGBA()
+		return alpha>>8 == 0xff && r>>8 == 0xff && g>>8 == 0 && b>>8 == 0
+	}
+
+	// Walk inward from the circle's radius along angle, counting consecutive
+	// red (border) pixels.
+	thickness := func(angle float64) int {
+		dx, dy := math.Cos(angle), math.Sin(angle)
+		r := math.Min(cx, cy)
+		count := 0
+		for d := r; d > 0; d-- {
+			x, y := int(cx+dx*d), int(cy+dy*d)
+			if isRed(x, y) {
+				count++
+			} else if count > 0 {
+				break
+			}
+		}
+		return count
+	}
+
+	cardinal := thickness(0)
+	diagonal := thickness(math.Pi / 4)
+	if cardinal == 0 || diagonal == 0 {
+		t.Fatalf("no border pixels found: cardinal=%d diagonal=%d", cardinal, diagonal)
+	}
+	if d := cardinal - diagonal; d < -1 || d > 1 {
+		t.Errorf("border thickness varies by angle: cardinal=%dpx diagonal=%dpx, want within 1px", cardinal, diagonal)
+	}
+}
+
+func TestApplyShapeNoBorderColorSkipsBorder(t *testing.T) {
+	a := NewAvatar().ConfigureShape(ShapeCircle, "", 10)
+	rgba := opaqueRGBA(a.Width, a.Height)
+
+	if err := a.applyShape(rgba); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, alpha := rgba.At(a.Width/2, a.Height/2).RGBA(); alpha>>8 != 0xff {
+		t.Errorf("center alpha = %d, want opaque", alpha>>8)
+	}
+
+	// Just inside the circle's top edge, where a 10px border would be drawn.
+	edge := rgba.At(a.Width/2, 1)
+	if r, g, b, _ := edge.RGBA(); r>>8 != 0xff || g>>8 != 0xff || b>>8 != 0xff {
+		t.Errorf("edge pixel = %v, want the untouched white fill (no border)", edge)
+	}
+}